@@ -0,0 +1,116 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"portainer-mcp-wrapper/internal/config"
+)
+
+// boundServer pairs an http.Server with the listener it serves, so the
+// caller can start and stop a whole fleet of PROTO://ADDR listeners
+// (tcp/unix/tls) that all share the same handler.
+type boundServer struct {
+	spec string
+	srv  *http.Server
+	ln   net.Listener
+}
+
+// buildListeners creates one boundServer per spec in cfg.ListenAddrs,
+// mirroring how dockerd's ServeApi fans out across PROTO://ADDR args. All
+// servers share handler and timeouts; only the transport differs.
+func buildListeners(cfg *config.Config, handler http.Handler) ([]*boundServer, error) {
+	var servers []*boundServer
+	for _, spec := range cfg.ListenAddrs {
+		proto, addr, found := strings.Cut(spec, "://")
+		if !found {
+			return nil, fmt.Errorf("invalid listen spec %q, want proto://addr", spec)
+		}
+
+		ln, err := newListener(proto, addr, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on %q: %w", spec, err)
+		}
+
+		servers = append(servers, &boundServer{
+			spec: spec,
+			ln:   ln,
+			srv: &http.Server{
+				Handler:      handler,
+				ReadTimeout:  15 * time.Second,
+				WriteTimeout: 15 * time.Second,
+				IdleTimeout:  60 * time.Second,
+			},
+		})
+	}
+	return servers, nil
+}
+
+// newListener opens the raw net.Listener for one PROTO://ADDR spec.
+func newListener(proto, addr string, cfg *config.Config) (net.Listener, error) {
+	switch proto {
+	case "tcp":
+		return net.Listen("tcp", addr)
+
+	case "unix":
+		if _, err := os.Stat(addr); err == nil {
+			os.Remove(addr)
+		}
+		return net.Listen("unix", addr)
+
+	case "tls":
+		tlsConfig, err := buildTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return tls.Listen("tcp", addr, tlsConfig)
+
+	default:
+		return nil, fmt.Errorf("unsupported listen protocol %q (want tcp, unix, or tls)", proto)
+	}
+}
+
+// buildTLSConfig loads the server certificate and, when
+// cfg.TLSClientCAFile is set, enables mTLS by requiring and verifying
+// client certificates against that CA bundle.
+func buildTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	if cfg.TLSClientCAFile != "" {
+		caPEM, err := os.ReadFile(cfg.TLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read MCP_TLS_CLIENT_CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no valid certificates found in MCP_TLS_CLIENT_CA")
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
+}
+
+// serveAll starts every boundServer concurrently, sending any non-graceful
+// Serve error on errCh.
+func serveAll(servers []*boundServer, errCh chan<- error) {
+	for _, bs := range servers {
+		go func(bs *boundServer) {
+			if err := bs.srv.Serve(bs.ln); err != nil && err != http.ErrServerClosed {
+				errCh <- fmt.Errorf("%s: %w", bs.spec, err)
+			}
+		}(bs)
+	}
+}
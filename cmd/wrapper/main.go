@@ -7,13 +7,18 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"runtime"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"portainer-mcp-wrapper/internal/audit"
 	"portainer-mcp-wrapper/internal/auth"
 	"portainer-mcp-wrapper/internal/bridge"
 	"portainer-mcp-wrapper/internal/config"
+	"portainer-mcp-wrapper/internal/docker"
 )
 
 func main() {
@@ -29,19 +34,53 @@ func main() {
 	if cfg.ReadOnlyMode {
 		log.Printf("WARNING: Running in READ-ONLY mode")
 	}
+	if cfg.DockerToolsEnabled {
+		log.Printf("Native Docker Engine API tools are ENABLED")
+	}
+
+	registry, err := auth.LoadRegistry(cfg.MCPTokensFile)
+	if err != nil {
+		log.Fatalf("Failed to load token registry: %v", err)
+	}
+
+	if cfg.AuditLogFile != "" {
+		auditFile, err := os.OpenFile(cfg.AuditLogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Fatalf("Failed to open audit log file: %v", err)
+		}
+		defer auditFile.Close()
+		audit.SetOutput(auditFile)
+	}
 
 	// Create context for lifecycle management
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Pool of warm portainer-mcp subprocesses shared across sessions so
+	// short-lived MCP connections don't each pay subprocess spawn cost
+	pool := bridge.NewPool(ctx, cfg.PoolMin, cfg.PoolMax, cfg.PoolIdleTTL)
+	go pool.RunReaper(ctx, cfg.PoolIdleTTL/2)
+
 	// Create MCP server factory function
 	// This is called for each new HTTP/SSE session
 	getServer := func(req *http.Request) *mcp.Server {
-		server, err := bridge.CreateMCPServer(ctx, cfg)
+		principal, _ := auth.PrincipalFromContext(req.Context())
+		server, release, err := pool.Acquire(cfg, principal)
 		if err != nil {
-			log.Printf("Failed to create MCP server: %v", err)
+			log.Printf("Failed to acquire MCP server from pool: %v", err)
 			return nil
 		}
+
+		if err := docker.RegisterTools(server, cfg, principal); err != nil {
+			log.Printf("Failed to register docker tools: %v", err)
+		}
+
+		// req.Context() only spans the initiating HTTP request (the SDK
+		// detaches it once the long-running stream takes over), not the MCP
+		// session's lifetime, so it can't drive release. Wait for the actual
+		// session this server ends up bound to instead.
+		go releaseWhenSessionEnds(server, release)
+
 		return server
 	}
 
@@ -52,50 +91,146 @@ func main() {
 	})
 
 	// Wrap with authentication middleware
-	authHandler := auth.NewAuthMiddleware(cfg.MCPAccessToken)(handler)
+	authHandler := auth.NewAuthMiddleware(registry)(handler)
 
 	// Create HTTP mux with routes
 	mux := http.NewServeMux()
 	mux.Handle("/", authHandler)
 	mux.HandleFunc("/health", healthCheckHandler)
 
-	// Create HTTP server
-	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%d", cfg.MCPPort),
-		Handler:      mux,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+	// Bind every configured listen spec (tcp/unix/tls) to the same mux
+	servers, err := buildListeners(cfg, mux)
+	if err != nil {
+		log.Fatalf("Failed to set up listeners: %v", err)
 	}
 
-	// Graceful shutdown handling
-	go func() {
-		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-		<-sigChan
+	// Graceful shutdown handling, with escalating behavior on repeated signals
+	go handleSignals(servers, cancel)
+
+	errCh := make(chan error, len(servers))
+	serveAll(servers, errCh)
 
-		log.Println("Shutdown signal received, stopping server...")
+	for _, bs := range servers {
+		log.Printf("Portainer MCP Wrapper listening on %s", bs.spec)
+	}
 
-		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer shutdownCancel()
+	select {
+	case err := <-errCh:
+		log.Fatalf("Server failed: %v", err)
+	case <-ctx.Done():
+	}
+
+	log.Println("Server stopped gracefully")
+}
 
-		if err := srv.Shutdown(shutdownCtx); err != nil {
-			log.Printf("Server shutdown error: %v", err)
+// sessionAttachTimeout bounds how long releaseWhenSessionEnds will wait for
+// a freshly acquired server to get bound to an MCP session before giving up
+// and releasing anyway, so a pooled child isn't held forever by a session
+// whose initialize handshake never completes.
+const sessionAttachTimeout = 10 * time.Second
+
+// releaseWhenSessionEnds waits for the MCP session that ends up bound to
+// server to actually end - client disconnects, the session's idle timeout
+// fires, or it's closed explicitly - and only then calls release. getServer
+// hands back a fresh *mcp.Server per session, so server carries at most one
+// ServerSession once mcp.NewStreamableHTTPHandler connects it.
+func releaseWhenSessionEnds(server *mcp.Server, release func()) {
+	deadline := time.Now().Add(sessionAttachTimeout)
+	var sess *mcp.ServerSession
+	for sess == nil && time.Now().Before(deadline) {
+		for s := range server.Sessions() {
+			sess = s
+			break
+		}
+		if sess == nil {
+			time.Sleep(10 * time.Millisecond)
 		}
+	}
+	if sess == nil {
+		log.Printf("MCP session never attached within %s, releasing pooled subprocess", sessionAttachTimeout)
+		release()
+		return
+	}
 
-		cancel() // Cancel main context
-	}()
+	sess.Wait()
+	release()
+}
 
-	// Start server
-	log.Printf("Portainer MCP Wrapper listening on :%d", cfg.MCPPort)
-	log.Printf("Health check available at http://localhost:%d/health", cfg.MCPPort)
-	log.Printf("MCP endpoint available at http://localhost:%d/", cfg.MCPPort)
+// handleSignals implements an escalating interrupt trap: the first
+// SIGINT/SIGTERM starts a graceful shutdown with a 10s deadline, a second
+// signal shortens that deadline to 2s, and a third skips cleanup entirely
+// and force-exits with the conventional 128+signal status. When DEBUG=1,
+// SIGQUIT additionally dumps all goroutine stacks and exits immediately,
+// matching how Docker/containerd-family daemons behave under operators.
+func handleSignals(servers []*boundServer, cancel context.CancelFunc) {
+	signals := []os.Signal{os.Interrupt, syscall.SIGTERM}
+	debug := os.Getenv("DEBUG") == "1"
+	if debug {
+		signals = append(signals, syscall.SIGQUIT)
+	}
 
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("Server failed: %v", err)
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, signals...)
+
+	var interrupts atomic.Uint32
+	var generation atomic.Uint32
+	var shutdownCancel context.CancelFunc
+
+	for sig := range sigChan {
+		if debug && sig == syscall.SIGQUIT {
+			log.Println("SIGQUIT received, dumping goroutines and exiting")
+			buf := make([]byte, 1<<20)
+			n := runtime.Stack(buf, true)
+			os.Stderr.Write(buf[:n])
+			os.Exit(1)
+		}
+
+		switch n := interrupts.Add(1); {
+		case n == 1:
+			log.Println("Shutdown signal received, stopping server (10s deadline)...")
+			shutdownCancel = shutdown(servers, cancel, 10*time.Second, shutdownCancel, &generation)
+		case n == 2:
+			log.Println("Second shutdown signal received, shortening deadline to 2s")
+			shutdownCancel = shutdown(servers, cancel, 2*time.Second, shutdownCancel, &generation)
+		default:
+			log.Printf("Third interrupt received, skipping cleanup and force-exiting")
+			sysSig, _ := sig.(syscall.Signal)
+			os.Exit(128 + int(sysSig))
+		}
 	}
+}
 
-	log.Println("Server stopped gracefully")
+// shutdown cancels any in-flight shutdown attempt and starts a new one with
+// the given deadline across every listener, returning its cancel func so the
+// caller can escalate again if another signal arrives. generation guards
+// cancelMain: a superseded attempt's Shutdown calls return early (with
+// context.Canceled) the instant a newer signal arrives, so without this
+// guard the stale goroutine would call cancelMain immediately instead of
+// letting the new deadline actually run.
+func shutdown(servers []*boundServer, cancelMain context.CancelFunc, deadline time.Duration, prevCancel context.CancelFunc, generation *atomic.Uint32) context.CancelFunc {
+	if prevCancel != nil {
+		prevCancel()
+	}
+	gen := generation.Add(1)
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), deadline)
+	go func() {
+		var wg sync.WaitGroup
+		for _, bs := range servers {
+			wg.Add(1)
+			go func(bs *boundServer) {
+				defer wg.Done()
+				if err := bs.srv.Shutdown(shutdownCtx); err != nil {
+					log.Printf("Server shutdown error on %s: %v", bs.spec, err)
+				}
+			}(bs)
+		}
+		wg.Wait()
+		if generation.Load() == gen {
+			cancelMain()
+		}
+	}()
+	return shutdownCancel
 }
 
 // healthCheckHandler provides a simple health check endpoint
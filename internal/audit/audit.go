@@ -0,0 +1,60 @@
+// Package audit provides an append-only JSONL audit log for MCP tool
+// calls: one line per call recording who made it, what it touched, how
+// long it took, and how it ended.
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Record is one audit log entry for a single MCP tool call.
+type Record struct {
+	Timestamp time.Time `json:"timestamp"`
+	Principal string    `json:"principal"`
+	Tool      string    `json:"tool"`
+	ArgsHash  string    `json:"args_hash"`
+	LatencyMS int64     `json:"latency_ms"`
+	Outcome   string    `json:"outcome"`
+}
+
+var (
+	mu     sync.Mutex
+	writer io.Writer = os.Stdout
+)
+
+// SetOutput redirects future Log calls to w. Defaults to os.Stdout.
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	writer = w
+}
+
+// Log appends rec as a single JSON line. Marshal failures are dropped
+// rather than surfaced, since a malformed record must never block or fail
+// the tool call it's auditing.
+func Log(rec Record) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	data = append(data, '\n')
+
+	mu.Lock()
+	defer mu.Unlock()
+	writer.Write(data)
+}
+
+// HashArgs returns a stable hex-encoded SHA-256 hash of a tool call's
+// arguments, so the audit log records what shape of call was made without
+// persisting potentially sensitive argument values.
+func HashArgs(args any) string {
+	data, _ := json.Marshal(args)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -1,36 +1,67 @@
 package auth
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
+
+	"portainer-mcp-wrapper/internal/errdefs"
 )
 
-// NewAuthMiddleware creates middleware that validates Bearer token
-func NewAuthMiddleware(expectedToken string) func(http.Handler) http.Handler {
+// NewAuthMiddleware creates middleware that validates a bearer token
+// against registry, stashes the resolved Principal on the request context
+// for downstream handlers (the bridge's tool filtering, the audit log),
+// and rejects the request once the principal's rate limit is exceeded.
+func NewAuthMiddleware(registry *Registry) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Extract Authorization header
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				http.Error(w, `{"error":"missing authorization header"}`, http.StatusUnauthorized)
+				writeUnauthorized(w, errors.New("missing authorization header"))
 				return
 			}
 
 			// Check Bearer token format
 			parts := strings.SplitN(authHeader, " ", 2)
 			if len(parts) != 2 || parts[0] != "Bearer" {
-				http.Error(w, `{"error":"invalid authorization format"}`, http.StatusUnauthorized)
+				writeUnauthorized(w, errors.New("invalid authorization format"))
+				return
+			}
+
+			// Validate token against the registry
+			principal, ok := registry.Authenticate(parts[1])
+			if !ok {
+				writeUnauthorized(w, errors.New("invalid token"))
 				return
 			}
 
-			// Validate token
-			if parts[1] != expectedToken {
-				http.Error(w, `{"error":"invalid token"}`, http.StatusUnauthorized)
+			if !principal.limiter.Allow() {
+				writeTooManyRequests(w, principal)
 				return
 			}
 
-			// Token valid, proceed
-			next.ServeHTTP(w, r)
+			// Token valid, proceed with the principal attached
+			next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), principal)))
 		})
 	}
 }
+
+// writeUnauthorized writes err as an errdefs.Payload so MCP clients get the
+// same {"code": "UNAUTHORIZED", "message": ...} shape the bridge uses for
+// upstream Portainer 401 responses, instead of an ad-hoc error string.
+func writeUnauthorized(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(errdefs.ToPayload(errdefs.AsUnauthorized(err)))
+}
+
+// writeTooManyRequests rejects a request that exceeded its principal's
+// rate_limit_rps.
+func writeTooManyRequests(w http.ResponseWriter, principal *Principal) {
+	err := errdefs.AsUnavailable(errors.New("rate limit exceeded for principal " + principal.Name))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(errdefs.ToPayload(err))
+}
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// tokenEntry is the on-disk shape of one entry in MCP_TOKENS_FILE.
+type tokenEntry struct {
+	Name             string   `json:"name" yaml:"name"`
+	AllowedTools     []string `json:"allowed_tools" yaml:"allowed_tools"`
+	ReadOnlyOverride *bool    `json:"read_only_override,omitempty" yaml:"read_only_override,omitempty"`
+	RateLimitRPS     float64  `json:"rate_limit_rps,omitempty" yaml:"rate_limit_rps,omitempty"`
+}
+
+// Principal is the resolved identity behind a validated bearer token,
+// stashed on the request context so downstream code (the bridge's tool
+// filtering, the audit log) can act on it without re-parsing the token.
+type Principal struct {
+	Name             string
+	AllowedTools     []string
+	ReadOnlyOverride *bool
+
+	limiter *rateLimiter
+}
+
+// Allows reports whether tool matches one of the principal's allowed_tools
+// glob patterns. A principal with no patterns allows nothing.
+func (p *Principal) Allows(tool string) bool {
+	for _, pattern := range p.AllowedTools {
+		if ok, err := path.Match(pattern, tool); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Registry is a set of principals keyed by their bearer token, loaded from
+// MCP_TOKENS_FILE.
+type Registry struct {
+	principals map[string]*Principal
+}
+
+// LoadRegistry reads a JSON or YAML token registry file (format chosen by
+// extension; .yaml/.yml decode as YAML, everything else as JSON) mapping
+// token -> {name, allowed_tools, read_only_override, rate_limit_rps}.
+func LoadRegistry(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token registry %q: %w", path, err)
+	}
+
+	entries := make(map[string]tokenEntry)
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+		if err := yaml.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("failed to parse token registry %q as YAML: %w", path, err)
+		}
+	} else {
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("failed to parse token registry %q as JSON: %w", path, err)
+		}
+	}
+
+	principals := make(map[string]*Principal, len(entries))
+	for token, entry := range entries {
+		if entry.Name == "" {
+			return nil, fmt.Errorf("token registry %q: entry is missing a name", path)
+		}
+		principals[token] = &Principal{
+			Name:             entry.Name,
+			AllowedTools:     entry.AllowedTools,
+			ReadOnlyOverride: entry.ReadOnlyOverride,
+			limiter:          newRateLimiter(entry.RateLimitRPS),
+		}
+	}
+
+	return &Registry{principals: principals}, nil
+}
+
+// Authenticate looks up token in the registry, returning the matching
+// principal or false if the token is unknown.
+func (r *Registry) Authenticate(token string) (*Principal, bool) {
+	p, ok := r.principals[token]
+	return p, ok
+}
+
+// rateLimiter is a minimal token bucket: one token refills every 1/rps,
+// capped at a burst of 1 second's worth of tokens (at least one). rps <= 0
+// means unlimited.
+type rateLimiter struct {
+	rps float64
+
+	mu       sync.Mutex
+	tokens   float64
+	lastFill time.Time
+}
+
+func newRateLimiter(rps float64) *rateLimiter {
+	return &rateLimiter{rps: rps, tokens: rps, lastFill: time.Now()}
+}
+
+// Allow reports whether a call may proceed now, consuming one token if so.
+func (l *rateLimiter) Allow() bool {
+	if l == nil || l.rps <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	burst := l.rps
+	if burst < 1 {
+		burst = 1
+	}
+	l.tokens += now.Sub(l.lastFill).Seconds() * l.rps
+	if l.tokens > burst {
+		l.tokens = burst
+	}
+	l.lastFill = now
+
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// contextKey avoids collisions with context keys from other packages.
+type contextKey string
+
+const principalContextKey contextKey = "mcp-principal"
+
+// withPrincipal returns a copy of ctx carrying p.
+func withPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey, p)
+}
+
+// PrincipalFromContext returns the principal stashed by the auth
+// middleware, if any.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalContextKey).(*Principal)
+	return p, ok
+}
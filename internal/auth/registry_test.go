@@ -0,0 +1,56 @@
+package auth
+
+import "testing"
+
+func TestPrincipalAllows(t *testing.T) {
+	p := &Principal{AllowedTools: []string{"docker_container_*", "portainer_list_endpoints"}}
+
+	cases := map[string]bool{
+		"docker_container_list":    true,
+		"docker_container_inspect": true,
+		"docker_volume_list":       false,
+		"portainer_list_endpoints": true,
+		"portainer_delete_stack":   false,
+	}
+	for tool, want := range cases {
+		if got := p.Allows(tool); got != want {
+			t.Errorf("Allows(%q) = %v, want %v", tool, got, want)
+		}
+	}
+}
+
+func TestPrincipalAllowsNothingWithoutPatterns(t *testing.T) {
+	p := &Principal{}
+	if p.Allows("anything") {
+		t.Fatal("a principal with no allowed_tools patterns should allow nothing")
+	}
+}
+
+func TestRateLimiterAllowsUnderBurst(t *testing.T) {
+	l := newRateLimiter(2)
+	if !l.Allow() {
+		t.Fatal("first call should be allowed (full burst)")
+	}
+	if !l.Allow() {
+		t.Fatal("second call should be allowed (burst of 2)")
+	}
+	if l.Allow() {
+		t.Fatal("third immediate call should be rate limited")
+	}
+}
+
+func TestRateLimiterUnlimitedWhenRPSNonPositive(t *testing.T) {
+	l := newRateLimiter(0)
+	for i := 0; i < 100; i++ {
+		if !l.Allow() {
+			t.Fatal("rps <= 0 should mean unlimited")
+		}
+	}
+}
+
+func TestRateLimiterNilIsUnlimited(t *testing.T) {
+	var l *rateLimiter
+	if !l.Allow() {
+		t.Fatal("a nil rateLimiter should allow every call")
+	}
+}
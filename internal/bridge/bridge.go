@@ -2,70 +2,280 @@ package bridge
 
 import (
 	"context"
-	"os/exec"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"portainer-mcp-wrapper/internal/audit"
+	"portainer-mcp-wrapper/internal/auth"
 	"portainer-mcp-wrapper/internal/config"
+	"portainer-mcp-wrapper/internal/errdefs"
 )
 
-// CreateMCPServer creates an MCP server that proxies to Portainer MCP subprocess
-// NOTE: This is a simplified version. The actual bridge implementation will be
-// refined once we can test with the Portainer MCP binary in Docker.
-func CreateMCPServer(ctx context.Context, cfg *config.Config) (*mcp.Server, error) {
-	// Build command arguments for Portainer MCP
-	args := []string{
-		"--server", cfg.PortainerURL,
-		"--api-token", cfg.PortainerAPIToken,
+// protocolVersion is the MCP protocol version the wrapper requests in its
+// initialize handshake with the portainer-mcp child.
+const protocolVersion = "2024-11-05"
+
+// session ties one portainer-mcp subprocess to the rpcClient that talks to
+// it. A Pool shares a session across Streamable-HTTP client sessions; the
+// subprocess is not killed when one of them disconnects.
+type session struct {
+	cfg   *config.Config
+	child *childProcess
+	rpc   *rpcClient
+}
+
+// buildServer fetches the tool/resource/prompt lists a session's child
+// advertises and registers proxying handlers for each on a fresh
+// *mcp.Server. Called once a Pool has checked out (or spawned) the child a
+// session will borrow. When principal is non-nil, tools/list is filtered
+// down to the principal's allowed_tools glob patterns before it ever
+// reaches the mcp.Server.
+func buildServer(s *session, principal *auth.Principal) (*mcp.Server, error) {
+	tools, resources, prompts, err := s.fetchCapabilities()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch portainer-mcp capabilities: %w", err)
 	}
 
-	if cfg.MCPToolsFile != "" {
-		args = append(args, "--tools-file", cfg.MCPToolsFile)
+	impl := &mcp.Implementation{
+		Name:    "portainer-mcp-wrapper",
+		Version: "1.0.0",
 	}
-	if cfg.DisableVersionCheck {
-		args = append(args, "--disable-version-check")
+	server := mcp.NewServer(impl, &mcp.ServerOptions{})
+
+	for _, t := range tools {
+		if principal != nil && !principal.Allows(t.Name) {
+			continue
+		}
+		server.AddTool(t, s.proxyToolCall(t.Name, principal))
+	}
+	for _, r := range resources {
+		server.AddResource(r, s.proxyResourceRead(r.URI))
 	}
-	if cfg.ReadOnlyMode {
-		args = append(args, "--read-only")
+	for _, p := range prompts {
+		server.AddPrompt(p, s.proxyPromptGet(p.Name))
 	}
 
-	// Create command for Portainer MCP
-	cmd := exec.CommandContext(ctx, cfg.MCPBinaryPath, args...)
+	return server, nil
+}
 
-	// For now, store the command (we'll implement the actual bridge in Docker)
-	_ = cmd
+// start spawns the child and performs the MCP initialize handshake,
+// mirroring what a normal MCP client would send over stdio.
+func (s *session) start(ctx context.Context) error {
+	child, err := newChildProcess(ctx, s.cfg)
+	if err != nil {
+		return err
+	}
+	s.child = child
+	s.rpc = newRPCClient(child.stdin, child.stdout, s.handleNotification)
 
-	// Create MCP server with implementation details
-	impl := &mcp.Implementation{
-		Name:    "portainer-mcp-wrapper",
-		Version: "1.0.0",
+	go func() {
+		if err := s.rpc.run(); err != nil {
+			log.Printf("portainer-mcp rpc stream ended: %v", err)
+		}
+	}()
+
+	initParams := map[string]any{
+		"protocolVersion": protocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo": map[string]any{
+			"name":    "portainer-mcp-wrapper",
+			"version": "1.0.0",
+		},
 	}
+	if _, err := s.rpc.call("initialize", initParams); err != nil {
+		return fmt.Errorf("initialize handshake with portainer-mcp failed: %w", err)
+	}
+	if err := s.rpc.send(&jsonrpcMessage{JSONRPC: "2.0", Method: "notifications/initialized"}); err != nil {
+		return fmt.Errorf("failed to ack initialize: %w", err)
+	}
+	return nil
+}
 
-	// Create server with minimal options
-	server := mcp.NewServer(impl, &mcp.ServerOptions{})
+// fetchCapabilities queries the child for everything it advertises so the
+// wrapper's own server can merge those lists into what it exposes upstream.
+func (s *session) fetchCapabilities() ([]*mcp.Tool, []*mcp.Resource, []*mcp.Prompt, error) {
+	var tools struct {
+		Tools []*mcp.Tool `json:"tools"`
+	}
+	if raw, err := s.rpc.call("tools/list", map[string]any{}); err == nil {
+		json.Unmarshal(raw, &tools)
+	} else {
+		return nil, nil, nil, fmt.Errorf("tools/list failed: %w", err)
+	}
 
-	// TODO: Implement actual transport bridge
-	// This will be completed when testing in Docker with Portainer MCP binary
+	var resources struct {
+		Resources []*mcp.Resource `json:"resources"`
+	}
+	if raw, err := s.rpc.call("resources/list", map[string]any{}); err == nil {
+		json.Unmarshal(raw, &resources)
+	}
 
-	return server, nil
+	var prompts struct {
+		Prompts []*mcp.Prompt `json:"prompts"`
+	}
+	if raw, err := s.rpc.call("prompts/list", map[string]any{}); err == nil {
+		json.Unmarshal(raw, &prompts)
+	}
+
+	return tools.Tools, resources.Resources, prompts.Prompts, nil
 }
 
-// GetPortainerCommand builds the Portainer MCP command for manual execution
-// This is a helper function for debugging
-func GetPortainerCommand(cfg *config.Config) *exec.Cmd {
-	args := []string{
-		"--server", cfg.PortainerURL,
-		"--api-token", cfg.PortainerAPIToken,
+// proxyToolCall builds an mcp.ToolHandler that forwards tools/call for the
+// given tool name to the portainer-mcp subprocess and relays its result.
+// Upstream failures are classified via errdefs and returned as an
+// isError tool result carrying a machine-readable code, rather than a raw
+// JSON-RPC error, so LLM clients can branch on err.Code. Every call is
+// recorded to the audit log regardless of outcome. principal re-checks
+// authorization even though buildServer already filtered tools/list, as a
+// defense-in-depth guard against a client that cached an older tool list.
+func (s *session) proxyToolCall(name string, principal *auth.Principal) mcp.ToolHandler {
+	return func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+
+		if principal != nil && !principal.Allows(name) {
+			result := toolErrorResult(errdefs.AsForbidden(fmt.Errorf("tool %q is not permitted for this token", name)))
+			auditToolCall(principal, name, req.Params.Arguments, start, "forbidden")
+			return result, nil
+		}
+
+		raw, err := s.rpc.call("tools/call", map[string]any{
+			"name":      name,
+			"arguments": req.Params.Arguments,
+		})
+		if err != nil {
+			auditToolCall(principal, name, req.Params.Arguments, start, "error")
+			return toolErrorResult(classifyRPCError(err)), nil
+		}
+
+		var result mcp.CallToolResult
+		if err := json.Unmarshal(raw, &result); err != nil {
+			auditToolCall(principal, name, req.Params.Arguments, start, "decode_error")
+			return nil, fmt.Errorf("failed to decode portainer-mcp response for tool %q: %w", name, err)
+		}
+
+		outcome := "ok"
+		if result.IsError {
+			outcome = "tool_error"
+		}
+		auditToolCall(principal, name, req.Params.Arguments, start, outcome)
+		return &result, nil
 	}
+}
 
-	if cfg.MCPToolsFile != "" {
-		args = append(args, "--tools-file", cfg.MCPToolsFile)
+// auditToolCall appends one audit.Record for a completed tool call. args is
+// passed through as the raw JSON the client sent, since audit.HashArgs only
+// needs bytes to hash, not a decoded value.
+func auditToolCall(principal *auth.Principal, tool string, args json.RawMessage, start time.Time, outcome string) {
+	name := "unauthenticated"
+	if principal != nil {
+		name = principal.Name
 	}
-	if cfg.DisableVersionCheck {
-		args = append(args, "--disable-version-check")
+	audit.Log(audit.Record{
+		Timestamp: time.Now(),
+		Principal: name,
+		Tool:      tool,
+		ArgsHash:  audit.HashArgs(args),
+		LatencyMS: time.Since(start).Milliseconds(),
+		Outcome:   outcome,
+	})
+}
+
+// proxyResourceRead builds an mcp.ResourceHandler that forwards
+// resources/read for the given URI to the subprocess.
+func (s *session) proxyResourceRead(uri string) mcp.ResourceHandler {
+	return func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		raw, err := s.rpc.call("resources/read", map[string]any{"uri": uri})
+		if err != nil {
+			return nil, classifyRPCError(err)
+		}
+		var result mcp.ReadResourceResult
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return nil, fmt.Errorf("failed to decode portainer-mcp response for resource %q: %w", uri, err)
+		}
+		return &result, nil
 	}
-	if cfg.ReadOnlyMode {
-		args = append(args, "--read-only")
+}
+
+// proxyPromptGet builds an mcp.PromptHandler that forwards prompts/get for
+// the given prompt name to the subprocess.
+func (s *session) proxyPromptGet(name string) mcp.PromptHandler {
+	return func(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		raw, err := s.rpc.call("prompts/get", map[string]any{
+			"name":      name,
+			"arguments": req.Params.Arguments,
+		})
+		if err != nil {
+			return nil, classifyRPCError(err)
+		}
+		var result mcp.GetPromptResult
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return nil, fmt.Errorf("failed to decode portainer-mcp response for prompt %q: %w", name, err)
+		}
+		return &result, nil
 	}
+}
 
-	return exec.Command(cfg.MCPBinaryPath, args...)
+// classifyRPCError maps a JSON-RPC error returned by the portainer-mcp
+// subprocess into the errdefs taxonomy, using the upstream Portainer HTTP
+// status it embedded when available.
+func classifyRPCError(err error) error {
+	rpcErr, ok := err.(*jsonrpcError)
+	if !ok {
+		return errdefs.AsSystem(err)
+	}
+	if status, ok := rpcErr.httpStatus(); ok {
+		return errdefs.FromHTTPStatus(status, rpcErr)
+	}
+	return errdefs.AsSystem(rpcErr)
+}
+
+// toolErrorResult renders err as an isError MCP tool result whose text
+// content is a JSON errdefs.Payload, so callers get a stable `code` field
+// instead of having to parse English error text.
+func toolErrorResult(err error) *mcp.CallToolResult {
+	data, marshalErr := json.Marshal(errdefs.ToPayload(err))
+	if marshalErr != nil {
+		data = []byte(fmt.Sprintf(`{"code":"SYSTEM","message":%q}`, err.Error()))
+	}
+	return &mcp.CallToolResult{
+		IsError: true,
+		Content: []mcp.Content{&mcp.TextContent{Text: string(data)}},
+	}
+}
+
+// handleNotification logs server-initiated notifications from the child.
+// list-changed notifications would require re-registering tools with the
+// mcp.Server; today we only surface them so operators notice upstream
+// changes until that plumbing lands.
+func (s *session) handleNotification(method string, params json.RawMessage) {
+	log.Printf("portainer-mcp notification: %s %s", method, string(params))
+}
+
+// supervise restarts the subprocess with exponential backoff whenever it
+// exits, until ctx is cancelled or a restart attempt fails.
+func (s *session) supervise(ctx context.Context) {
+	for {
+		err := s.child.wait()
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("portainer-mcp subprocess exited: %v", err)
+
+		if restartErr := s.child.restart(ctx); restartErr != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("failed to restart portainer-mcp subprocess: %v", restartErr)
+			return
+		}
+		s.rpc = newRPCClient(s.child.stdin, s.child.stdout, s.handleNotification)
+		go func() {
+			if err := s.rpc.run(); err != nil {
+				log.Printf("portainer-mcp rpc stream ended: %v", err)
+			}
+		}()
+	}
 }
@@ -0,0 +1,241 @@
+package bridge
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"portainer-mcp-wrapper/internal/auth"
+	"portainer-mcp-wrapper/internal/config"
+)
+
+// poolKey identifies a class of interchangeable portainer-mcp subprocesses.
+// Two sessions can only share a child if they'd have spawned it with the
+// same arguments.
+type poolKey struct {
+	toolsFile string
+	readOnly  bool
+	tokenHash string
+}
+
+func newPoolKey(cfg *config.Config) poolKey {
+	sum := sha256.Sum256([]byte(cfg.PortainerAPIToken))
+	return poolKey{
+		toolsFile: cfg.MCPToolsFile,
+		readOnly:  cfg.ReadOnlyMode,
+		tokenHash: hex.EncodeToString(sum[:]),
+	}
+}
+
+// effectiveConfig returns a copy of cfg with ReadOnlyMode resolved for
+// principal: principal.ReadOnlyOverride wins over cfg.ReadOnlyMode when set,
+// so a token's read-only status is enforced regardless of the wrapper's
+// global default. Keying pooled children on the resolved value (via
+// newPoolKey) keeps a relaxed or restricted token from ever sharing a
+// subprocess with one that resolved differently.
+func effectiveConfig(cfg *config.Config, principal *auth.Principal) *config.Config {
+	if principal == nil || principal.ReadOnlyOverride == nil {
+		return cfg
+	}
+	resolved := *cfg
+	resolved.ReadOnlyMode = *principal.ReadOnlyOverride
+	return &resolved
+}
+
+// pooledChild is one warm portainer-mcp subprocess kept alive by a Pool and
+// shared across concurrent MCP sessions that hash to the same poolKey.
+type pooledChild struct {
+	sess   *session
+	mu     sync.Mutex
+	refs   int
+	idleAt time.Time
+}
+
+// Pool keeps a small number of warm portainer-mcp subprocesses per poolKey
+// so that short-lived MCP sessions (the common case for LLM agents that
+// open and close connections rapidly) don't each pay subprocess spawn cost.
+type Pool struct {
+	min     int
+	max     int
+	idleTTL time.Duration
+	// lifetimeCtx governs pooled children: they're spawned against this
+	// context, not a borrowing session's per-request context, so one
+	// session disconnecting doesn't kill a child other sessions still
+	// share. It's cancelled when the process shuts down.
+	lifetimeCtx context.Context
+
+	mu       sync.Mutex
+	children map[poolKey][]*pooledChild
+}
+
+// NewPool creates a Pool governed by the given bounds. min is the number of
+// warm children kept per key even when idle; max bounds how many concurrent
+// children a single key may grow to; idleTTL is how long an idle child
+// beyond min survives before being evicted. ctx bounds the lifetime of every
+// pooled child the Pool spawns; it should live as long as the process, not
+// any single caller's request.
+func NewPool(ctx context.Context, min, max int, idleTTL time.Duration) *Pool {
+	if max < min {
+		max = min
+	}
+	return &Pool{
+		min:         min,
+		max:         max,
+		idleTTL:     idleTTL,
+		lifetimeCtx: ctx,
+		children:    make(map[poolKey][]*pooledChild),
+	}
+}
+
+// Acquire returns an *mcp.Server wired to a warm (or newly spawned) child
+// matching cfg, plus a release func the caller must invoke when the MCP
+// session that borrowed it ends. When principal is non-nil, the server only
+// exposes the tools principal is allowed to call, and a non-nil
+// principal.ReadOnlyOverride takes precedence over cfg.ReadOnlyMode for the
+// child spawned on its behalf. Note that a newly spawned child outlives the
+// caller's own context: it's kept alive for other sessions that may share
+// it, governed by the Pool's lifetime context instead.
+func (p *Pool) Acquire(cfg *config.Config, principal *auth.Principal) (*mcp.Server, func(), error) {
+	cfg = effectiveConfig(cfg, principal)
+	key := newPoolKey(cfg)
+
+	pc, err := p.checkout(cfg, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	server, err := buildServer(pc.sess, principal)
+	if err != nil {
+		p.release(key, pc)
+		return nil, nil, err
+	}
+
+	released := false
+	release := func() {
+		if released {
+			return
+		}
+		released = true
+		p.release(key, pc)
+	}
+	return server, release, nil
+}
+
+// checkout returns a child for key, growing the pool with a newly spawned
+// child whenever every existing one is already borrowed and key hasn't
+// reached max yet; otherwise it returns whichever existing child has the
+// fewest current borrowers, so load spreads across the pool instead of
+// piling onto a single child.
+func (p *Pool) checkout(cfg *config.Config, key poolKey) (*pooledChild, error) {
+	p.mu.Lock()
+	var least *pooledChild
+	for _, pc := range p.children[key] {
+		pc.mu.Lock()
+		isLeast := least == nil || pc.refs < least.refs
+		idle := pc.refs == 0
+		pc.mu.Unlock()
+		if idle {
+			least = pc
+			break
+		}
+		if isLeast {
+			least = pc
+		}
+	}
+	canGrow := len(p.children[key]) < p.max
+	if least != nil && (!canGrow || least.refs == 0) {
+		least.mu.Lock()
+		least.refs++
+		least.idleAt = time.Time{}
+		least.mu.Unlock()
+		p.mu.Unlock()
+		return least, nil
+	}
+	if !canGrow {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("pool exhausted: %d/%d portainer-mcp subprocesses already running for this config", len(p.children[key]), p.max)
+	}
+	p.mu.Unlock()
+
+	sess := &session{cfg: cfg}
+	if err := sess.start(p.lifetimeCtx); err != nil {
+		return nil, fmt.Errorf("failed to spawn pooled portainer-mcp subprocess: %w", err)
+	}
+	go sess.supervise(p.lifetimeCtx)
+
+	pc := &pooledChild{sess: sess, refs: 1}
+
+	p.mu.Lock()
+	p.children[key] = append(p.children[key], pc)
+	p.mu.Unlock()
+
+	return pc, nil
+}
+
+// release decrements the borrower count for pc and marks it idle once no
+// session is using it, making it eligible for eviction after idleTTL.
+func (p *Pool) release(key poolKey, pc *pooledChild) {
+	pc.mu.Lock()
+	pc.refs--
+	if pc.refs <= 0 {
+		pc.refs = 0
+		pc.idleAt = time.Now()
+	}
+	pc.mu.Unlock()
+}
+
+// Reap evicts idle children beyond min that have been idle longer than
+// idleTTL. Call it on a timer (e.g. from main) for the lifetime of the
+// process.
+func (p *Pool) Reap() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, children := range p.children {
+		survivors := make([]*pooledChild, 0, len(children))
+		remaining := len(children)
+
+		for _, pc := range children {
+			pc.mu.Lock()
+			idle := !pc.idleAt.IsZero() && time.Since(pc.idleAt) > p.idleTTL
+			pc.mu.Unlock()
+
+			if idle && remaining > p.min {
+				pc.sess.child.close()
+				remaining--
+				log.Printf("pool: evicted idle portainer-mcp subprocess")
+				continue
+			}
+			survivors = append(survivors, pc)
+		}
+		p.children[key] = survivors
+	}
+}
+
+// RunReaper periodically calls Reap until ctx is cancelled. interval
+// defaults to idleTTL/2 when non-positive.
+func (p *Pool) RunReaper(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = p.idleTTL / 2
+	}
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.Reap()
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package bridge
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"portainer-mcp-wrapper/internal/auth"
+	"portainer-mcp-wrapper/internal/config"
+)
+
+func TestCheckoutReusesIdleChild(t *testing.T) {
+	p := NewPool(context.Background(), 1, 2, 0)
+	cfg := &config.Config{}
+	key := newPoolKey(cfg)
+	idle := &pooledChild{sess: &session{cfg: cfg}}
+	p.children[key] = []*pooledChild{idle}
+
+	pc, err := p.checkout(cfg, key)
+	if err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+	if pc != idle {
+		t.Fatalf("checkout returned a different child instead of reusing the idle one")
+	}
+	if pc.refs != 1 {
+		t.Fatalf("refs = %d, want 1", pc.refs)
+	}
+	if len(p.children[key]) != 1 {
+		t.Fatalf("len(children) = %d, want 1 (no growth expected)", len(p.children[key]))
+	}
+}
+
+func TestCheckoutGrowsWhenAllBusyAndUnderMax(t *testing.T) {
+	p := NewPool(context.Background(), 1, 2, 0)
+	cfg := &config.Config{MCPBinaryPath: "/nonexistent/portainer-mcp-for-tests"}
+	key := newPoolKey(cfg)
+	busy := &pooledChild{sess: &session{cfg: cfg}, refs: 1}
+	p.children[key] = []*pooledChild{busy}
+
+	_, err := p.checkout(cfg, key)
+	if err == nil {
+		t.Fatal("expected an error spawning a nonexistent binary")
+	}
+	if !strings.Contains(err.Error(), "failed to spawn") {
+		t.Fatalf("checkout err = %q, want it to report a spawn failure (i.e. it attempted to grow)", err)
+	}
+}
+
+func TestCheckoutSharesLeastLoadedChildAtMax(t *testing.T) {
+	p := NewPool(context.Background(), 1, 2, 0)
+	cfg := &config.Config{}
+	key := newPoolKey(cfg)
+	busier := &pooledChild{sess: &session{cfg: cfg}, refs: 3}
+	lessBusy := &pooledChild{sess: &session{cfg: cfg}, refs: 1}
+	p.children[key] = []*pooledChild{busier, lessBusy}
+
+	pc, err := p.checkout(cfg, key)
+	if err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+	if pc != lessBusy {
+		t.Fatal("checkout should share the least-loaded existing child once the pool is at max")
+	}
+	if len(p.children[key]) != 2 {
+		t.Fatalf("len(children) = %d, want 2 (no growth expected at max)", len(p.children[key]))
+	}
+}
+
+func TestEffectiveConfigAppliesReadOnlyOverride(t *testing.T) {
+	cfg := &config.Config{ReadOnlyMode: false}
+
+	if got := effectiveConfig(cfg, nil); got != cfg {
+		t.Fatalf("nil principal should return cfg unchanged")
+	}
+
+	override := true
+	p := &auth.Principal{ReadOnlyOverride: &override}
+	resolved := effectiveConfig(cfg, p)
+	if !resolved.ReadOnlyMode {
+		t.Fatal("ReadOnlyOverride=true should force ReadOnlyMode on")
+	}
+	if cfg.ReadOnlyMode {
+		t.Fatal("effectiveConfig must not mutate the original cfg")
+	}
+}
@@ -0,0 +1,165 @@
+package bridge
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"portainer-mcp-wrapper/internal/config"
+)
+
+// restartBackoffBase is the initial delay between restart attempts after the
+// Portainer MCP subprocess crashes. Each further crash doubles the delay up
+// to restartBackoffMax; the crash count is never reset.
+const (
+	restartBackoffBase = 500 * time.Millisecond
+	restartBackoffMax  = 30 * time.Second
+)
+
+// childProcess manages the lifetime of a single portainer-mcp subprocess:
+// its stdio pipes, stderr draining, and crash/restart behavior.
+type childProcess struct {
+	cfg *config.Config
+
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	stdout  *bufio.Reader
+	crashes int
+}
+
+// newChildProcess spawns the portainer-mcp binary and wires up its pipes.
+// The child is placed in its own process group so cleanup can signal the
+// whole group rather than just the immediate PID.
+func newChildProcess(ctx context.Context, cfg *config.Config) (*childProcess, error) {
+	c := &childProcess{cfg: cfg}
+	if err := c.spawn(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func buildArgs(cfg *config.Config) []string {
+	args := []string{
+		"--server", cfg.PortainerURL,
+		"--api-token", cfg.PortainerAPIToken,
+	}
+	if cfg.MCPToolsFile != "" {
+		args = append(args, "--tools-file", cfg.MCPToolsFile)
+	}
+	if cfg.DisableVersionCheck {
+		args = append(args, "--disable-version-check")
+	}
+	if cfg.ReadOnlyMode {
+		args = append(args, "--read-only")
+	}
+	return args
+}
+
+func (c *childProcess) spawn(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, c.cfg.MCPBinaryPath, buildArgs(c.cfg)...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open stdout pipe: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open stderr pipe: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start portainer-mcp: %w", err)
+	}
+
+	go drainStderr(stderr)
+
+	c.mu.Lock()
+	c.cmd = cmd
+	c.stdin = stdin
+	c.stdout = bufio.NewReader(stdout)
+	c.mu.Unlock()
+
+	return nil
+}
+
+// drainStderr copies the child's stderr into the wrapper's own log output
+// line by line so operators see subprocess diagnostics in one place.
+func drainStderr(stderr io.ReadCloser) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		log.Printf("[portainer-mcp] %s", scanner.Text())
+	}
+}
+
+// restart kills the current process group (if still running) and starts a
+// fresh child, waiting out an exponential backoff based on how many times
+// the child has crashed since it was first spawned.
+func (c *childProcess) restart(ctx context.Context) error {
+	c.mu.Lock()
+	c.crashes++
+	crashes := c.crashes
+	c.mu.Unlock()
+
+	delay := restartBackoffBase << uint(crashes-1)
+	if delay > restartBackoffMax || delay <= 0 {
+		delay = restartBackoffMax
+	}
+
+	log.Printf("portainer-mcp subprocess exited unexpectedly (attempt %d), restarting in %s", crashes, delay)
+
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	c.closeLocked()
+	return c.spawn(ctx)
+}
+
+// wait blocks until the child process exits and returns its error, if any.
+func (c *childProcess) wait() error {
+	c.mu.Lock()
+	cmd := c.cmd
+	c.mu.Unlock()
+	if cmd == nil {
+		return fmt.Errorf("portainer-mcp subprocess was never started")
+	}
+	return cmd.Wait()
+}
+
+// closeLocked closes the stdin pipe and kills the process group of the
+// current child. It is safe to call even if the child already exited.
+func (c *childProcess) closeLocked() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stdin != nil {
+		c.stdin.Close()
+	}
+	if c.cmd != nil && c.cmd.Process != nil {
+		if pgid, err := syscall.Getpgid(c.cmd.Process.Pid); err == nil {
+			syscall.Kill(-pgid, syscall.SIGKILL)
+		} else {
+			c.cmd.Process.Kill()
+		}
+	}
+}
+
+// close terminates the subprocess and releases its resources. Safe to call
+// multiple times.
+func (c *childProcess) close() {
+	c.closeLocked()
+}
@@ -0,0 +1,186 @@
+package bridge
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// jsonrpcMessage is the wire format spoken by the portainer-mcp binary over
+// stdio: newline-delimited JSON-RPC 2.0, identical to what
+// mcp.NewStreamableHTTPHandler expects from transports on the server side.
+type jsonrpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *jsonrpcError   `json:"error,omitempty"`
+}
+
+type jsonrpcError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *jsonrpcError) Error() string {
+	return fmt.Sprintf("portainer-mcp error %d: %s", e.Code, e.Message)
+}
+
+// httpStatus extracts the upstream Portainer HTTP status code from the
+// error's Data field, when the portainer-mcp binary included one, so
+// callers can classify the error via errdefs.FromHTTPStatus.
+func (e *jsonrpcError) httpStatus() (int, bool) {
+	if len(e.Data) == 0 {
+		return 0, false
+	}
+	var data struct {
+		HTTPStatus int `json:"httpStatus"`
+	}
+	if err := json.Unmarshal(e.Data, &data); err != nil || data.HTTPStatus == 0 {
+		return 0, false
+	}
+	return data.HTTPStatus, true
+}
+
+// rpcClient speaks line-delimited JSON-RPC over a child process's stdin/
+// stdout and correlates responses back to their callers by request ID. It
+// also fans out server-initiated notifications (progress, log messages,
+// list-changed) to a single notification handler.
+type rpcClient struct {
+	writeMu sync.Mutex
+	writer  io.Writer
+	reader  *bufio.Reader
+
+	nextID int64
+
+	pendingMu sync.Mutex
+	pending   map[int64]chan *jsonrpcMessage
+
+	onNotify func(method string, params json.RawMessage)
+}
+
+func newRPCClient(w io.Writer, r *bufio.Reader, onNotify func(method string, params json.RawMessage)) *rpcClient {
+	return &rpcClient{
+		writer:   w,
+		reader:   r,
+		pending:  make(map[int64]chan *jsonrpcMessage),
+		onNotify: onNotify,
+	}
+}
+
+// call sends a JSON-RPC request and blocks until the matching response is
+// read by run, or the message stream is closed.
+func (c *rpcClient) call(method string, params any) (json.RawMessage, error) {
+	id := atomic.AddInt64(&c.nextID, 1)
+
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal params for %s: %w", method, err)
+	}
+
+	msg := jsonrpcMessage{
+		JSONRPC: "2.0",
+		ID:      json.RawMessage(fmt.Sprintf("%d", id)),
+		Method:  method,
+		Params:  rawParams,
+	}
+
+	ch := make(chan *jsonrpcMessage, 1)
+	c.pendingMu.Lock()
+	c.pending[id] = ch
+	c.pendingMu.Unlock()
+
+	if err := c.send(&msg); err != nil {
+		c.pendingMu.Lock()
+		delete(c.pending, id)
+		c.pendingMu.Unlock()
+		return nil, err
+	}
+
+	resp, ok := <-ch
+	if !ok {
+		return nil, fmt.Errorf("portainer-mcp subprocess closed before responding to %s", method)
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	return resp.Result, nil
+}
+
+func (c *rpcClient) send(msg *jsonrpcMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal jsonrpc message: %w", err)
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if _, err := c.writer.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write to portainer-mcp subprocess: %w", err)
+	}
+	return nil
+}
+
+// run reads framed messages until the stream ends, dispatching responses to
+// their waiting caller and notifications to onNotify. It returns when the
+// child's stdout is closed (process exited) or a read error occurs.
+func (c *rpcClient) run() error {
+	for {
+		line, err := c.reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var msg jsonrpcMessage
+			if jsonErr := json.Unmarshal(line, &msg); jsonErr != nil {
+				continue
+			}
+			c.dispatch(&msg)
+		}
+		if err != nil {
+			c.closeAllPending()
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed reading portainer-mcp subprocess stdout: %w", err)
+		}
+	}
+}
+
+func (c *rpcClient) dispatch(msg *jsonrpcMessage) {
+	if msg.Method != "" && len(msg.ID) == 0 {
+		if c.onNotify != nil {
+			c.onNotify(msg.Method, msg.Params)
+		}
+		return
+	}
+
+	var id int64
+	if err := json.Unmarshal(msg.ID, &id); err != nil {
+		return
+	}
+
+	c.pendingMu.Lock()
+	ch, ok := c.pending[id]
+	if ok {
+		delete(c.pending, id)
+	}
+	c.pendingMu.Unlock()
+
+	if ok {
+		ch <- msg
+		close(ch)
+	}
+}
+
+func (c *rpcClient) closeAllPending() {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+}
@@ -4,18 +4,50 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Config holds all configuration for the MCP wrapper
 type Config struct {
 	PortainerURL        string
 	PortainerAPIToken   string
-	MCPAccessToken      string
 	MCPPort             int
 	MCPToolsFile        string
 	DisableVersionCheck bool
 	ReadOnlyMode        bool
 	MCPBinaryPath       string
+
+	// PoolMin is the number of warm portainer-mcp subprocesses kept per
+	// config key even when idle. PoolMax bounds how many a single key may
+	// grow to under concurrent load. PoolIdleTTL is how long an idle
+	// subprocess beyond PoolMin survives before being reaped.
+	PoolMin     int
+	PoolMax     int
+	PoolIdleTTL time.Duration
+
+	// DockerToolsEnabled registers the native docker_* tools, which talk
+	// directly to the Docker Engine API instead of going through Portainer.
+	DockerToolsEnabled bool
+
+	// ListenAddrs is a list of PROTO://ADDR specs, e.g.
+	// "tcp://0.0.0.0:8080", "unix:///run/portainer-mcp.sock",
+	// "tls://0.0.0.0:8443". One http.Server is started per spec, all
+	// sharing the same mux and auth middleware. TLSCertFile/TLSKeyFile are
+	// required when any spec uses tls://; TLSClientCAFile additionally
+	// enables mTLS.
+	ListenAddrs     []string
+	TLSCertFile     string
+	TLSKeyFile      string
+	TLSClientCAFile string
+
+	// MCPTokensFile points at a JSON/YAML token registry mapping bearer
+	// token -> {name, allowed_tools, read_only_override, rate_limit_rps},
+	// replacing the single shared MCP_ACCESS_TOKEN with per-token scoped
+	// access. AuditLogFile is where the append-only JSONL audit log of
+	// tool calls is written; empty means stdout.
+	MCPTokensFile string
+	AuditLogFile  string
 }
 
 // LoadConfig loads configuration from environment variables
@@ -23,25 +55,57 @@ func LoadConfig() (*Config, error) {
 	cfg := &Config{
 		PortainerURL:        getEnvOrDefault("PORTAINER_URL", "http://portainer:9000"),
 		PortainerAPIToken:   os.Getenv("PORTAINER_API_TOKEN"),
-		MCPAccessToken:      os.Getenv("MCP_ACCESS_TOKEN"),
 		MCPPort:             getEnvAsIntOrDefault("MCP_PORT", 8080),
 		MCPToolsFile:        os.Getenv("MCP_TOOLS_FILE"),
 		DisableVersionCheck: getEnvAsBoolOrDefault("DISABLE_VERSION_CHECK", false),
 		ReadOnlyMode:        getEnvAsBoolOrDefault("READ_ONLY_MODE", false),
 		MCPBinaryPath:       getEnvOrDefault("MCP_BINARY_PATH", "/app/portainer-mcp"),
+		PoolMin:             getEnvAsIntOrDefault("POOL_MIN", 1),
+		PoolMax:             getEnvAsIntOrDefault("POOL_MAX", 4),
+		PoolIdleTTL:         getEnvAsDurationOrDefault("POOL_IDLE_TTL", 5*time.Minute),
+		DockerToolsEnabled:  getEnvAsBoolOrDefault("DOCKER_TOOLS_ENABLED", false),
+		TLSCertFile:         os.Getenv("MCP_TLS_CERT"),
+		TLSKeyFile:          os.Getenv("MCP_TLS_KEY"),
+		TLSClientCAFile:     os.Getenv("MCP_TLS_CLIENT_CA"),
+		MCPTokensFile:       os.Getenv("MCP_TOKENS_FILE"),
+		AuditLogFile:        os.Getenv("MCP_AUDIT_LOG_FILE"),
 	}
+	cfg.ListenAddrs = getEnvAsListenAddrs("MCP_LISTEN", cfg.MCPPort)
 
 	// Validate required fields
 	if cfg.PortainerAPIToken == "" {
 		return nil, fmt.Errorf("PORTAINER_API_TOKEN is required")
 	}
-	if cfg.MCPAccessToken == "" {
-		return nil, fmt.Errorf("MCP_ACCESS_TOKEN is required")
+	if cfg.MCPTokensFile == "" {
+		return nil, fmt.Errorf("MCP_TOKENS_FILE is required")
+	}
+	for _, addr := range cfg.ListenAddrs {
+		if strings.HasPrefix(addr, "tls://") && (cfg.TLSCertFile == "" || cfg.TLSKeyFile == "") {
+			return nil, fmt.Errorf("MCP_TLS_CERT and MCP_TLS_KEY are required for listen spec %q", addr)
+		}
 	}
 
 	return cfg, nil
 }
 
+// getEnvAsListenAddrs parses a comma-separated list of PROTO://ADDR specs
+// from the environment, defaulting to a single plain TCP listener on
+// defaultPort for backwards compatibility with MCP_PORT-only deployments.
+func getEnvAsListenAddrs(key string, defaultPort int) []string {
+	val := os.Getenv(key)
+	if val == "" {
+		return []string{fmt.Sprintf("tcp://0.0.0.0:%d", defaultPort)}
+	}
+
+	var addrs []string
+	for _, part := range strings.Split(val, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			addrs = append(addrs, part)
+		}
+	}
+	return addrs
+}
+
 // getEnvOrDefault returns environment variable value or default
 func getEnvOrDefault(key, defaultVal string) string {
 	if val := os.Getenv(key); val != "" {
@@ -69,3 +133,14 @@ func getEnvAsBoolOrDefault(key string, defaultVal bool) bool {
 	}
 	return defaultVal
 }
+
+// getEnvAsDurationOrDefault returns environment variable parsed as a
+// time.Duration (e.g. "5m", "30s") or default
+func getEnvAsDurationOrDefault(key string, defaultVal time.Duration) time.Duration {
+	if val := os.Getenv(key); val != "" {
+		if durVal, err := time.ParseDuration(val); err == nil {
+			return durVal
+		}
+	}
+	return defaultVal
+}
@@ -0,0 +1,334 @@
+// Package docker registers a set of MCP tools that talk directly to the
+// Docker Engine API, bypassing Portainer entirely. It mirrors the
+// registration pattern used by internal/bridge, but each tool is backed by
+// a local docker client call instead of a proxied subprocess request.
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"portainer-mcp-wrapper/internal/audit"
+	"portainer-mcp-wrapper/internal/auth"
+	"portainer-mcp-wrapper/internal/config"
+)
+
+// RegisterTools creates a Docker Engine API client and, when
+// cfg.DockerToolsEnabled, registers the native docker_* tools on server. When
+// principal is non-nil, only the tools it's allowed to call (per its
+// allowed_tools patterns) are registered at all, mirroring how the
+// Portainer-proxied tools are filtered in internal/bridge. It is a no-op when
+// the flag is off so operators who only want the Portainer-proxied tools see
+// no behavior change.
+func RegisterTools(server *mcp.Server, cfg *config.Config, principal *auth.Principal) error {
+	if !cfg.DockerToolsEnabled {
+		return nil
+	}
+
+	cli, err := newClient()
+	if err != nil {
+		return fmt.Errorf("failed to create docker engine client: %w", err)
+	}
+
+	for _, t := range tools(cli) {
+		if principal != nil && !principal.Allows(t.tool.Name) {
+			continue
+		}
+		server.AddTool(t.tool, auditedHandler(t.tool.Name, principal, t.handler))
+	}
+	return nil
+}
+
+// auditedHandler wraps handler so every docker_* tool call is recorded to the
+// audit log, the same way internal/bridge audits proxied Portainer tool
+// calls.
+func auditedHandler(name string, principal *auth.Principal, handler mcp.ToolHandler) mcp.ToolHandler {
+	return func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		result, err := handler(ctx, req)
+
+		outcome := "ok"
+		switch {
+		case err != nil:
+			outcome = "error"
+		case result != nil && result.IsError:
+			outcome = "tool_error"
+		}
+
+		principalName := "unauthenticated"
+		if principal != nil {
+			principalName = principal.Name
+		}
+		audit.Log(audit.Record{
+			Timestamp: time.Now(),
+			Principal: principalName,
+			Tool:      name,
+			ArgsHash:  audit.HashArgs(req.Params.Arguments),
+			LatencyMS: time.Since(start).Milliseconds(),
+			Outcome:   outcome,
+		})
+
+		return result, err
+	}
+}
+
+// newClient builds a Docker Engine API client against DOCKER_HOST, falling
+// back to the standard unix:///var/run/docker.sock when unset.
+func newClient() (*client.Client, error) {
+	return client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+}
+
+type registration struct {
+	tool    *mcp.Tool
+	handler mcp.ToolHandler
+}
+
+func tools(cli *client.Client) []registration {
+	return []registration{
+		{
+			tool: &mcp.Tool{
+				Name:        "docker_container_list",
+				Description: "List containers on the Docker host; stopped ones are included when all is true",
+				InputSchema: map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"all": map[string]any{"type": "boolean", "description": "include stopped containers"},
+					},
+				},
+			},
+			handler: containerListHandler(cli),
+		},
+		{
+			tool: &mcp.Tool{
+				Name:        "docker_container_logs",
+				Description: "Fetch recent stdout/stderr log lines for a container",
+				InputSchema: map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"container": map[string]any{"type": "string", "description": "container ID or name"},
+						"tail":      map[string]any{"type": "string", "description": "number of lines from the end, default \"200\""},
+					},
+					"required": []string{"container"},
+				},
+			},
+			handler: containerLogsHandler(cli),
+		},
+		{
+			tool: &mcp.Tool{
+				Name:        "docker_container_inspect",
+				Description: "Return the full inspect payload for a container",
+				InputSchema: map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"container": map[string]any{"type": "string", "description": "container ID or name"},
+					},
+					"required": []string{"container"},
+				},
+			},
+			handler: containerInspectHandler(cli),
+		},
+		{
+			tool: &mcp.Tool{
+				Name:        "docker_image_list",
+				Description: "List images present on the Docker host",
+				InputSchema: map[string]any{"type": "object", "properties": map[string]any{}},
+			},
+			handler: imageListHandler(cli),
+		},
+		{
+			tool: &mcp.Tool{
+				Name:        "docker_volume_list",
+				Description: "List volumes present on the Docker host",
+				InputSchema: map[string]any{"type": "object", "properties": map[string]any{}},
+			},
+			handler: volumeListHandler(cli),
+		},
+		{
+			tool: &mcp.Tool{
+				Name:        "docker_network_list",
+				Description: "List networks present on the Docker host",
+				InputSchema: map[string]any{"type": "object", "properties": map[string]any{}},
+			},
+			handler: networkListHandler(cli),
+		},
+		{
+			tool: &mcp.Tool{
+				Name:        "docker_events_stream",
+				Description: "Collect Docker daemon events for a short window (default 5s)",
+				InputSchema: map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"seconds": map[string]any{"type": "integer", "description": "how long to collect events, default 5"},
+					},
+				},
+			},
+			handler: eventsStreamHandler(cli),
+		},
+	}
+}
+
+func containerListHandler(cli *client.Client) mcp.ToolHandler {
+	return func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args struct {
+			All bool `json:"all"`
+		}
+		json.Unmarshal(req.Params.Arguments, &args)
+
+		containers, err := cli.ContainerList(ctx, container.ListOptions{All: args.All})
+		if err != nil {
+			return nil, fmt.Errorf("docker container list failed: %w", err)
+		}
+		return jsonResult(containers)
+	}
+}
+
+func containerLogsHandler(cli *client.Client) mcp.ToolHandler {
+	return func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args struct {
+			Container string `json:"container"`
+			Tail      string `json:"tail"`
+		}
+		json.Unmarshal(req.Params.Arguments, &args)
+		if args.Container == "" {
+			return nil, fmt.Errorf("container is required")
+		}
+		if args.Tail == "" {
+			args.Tail = "200"
+		}
+
+		info, err := cli.ContainerInspect(ctx, args.Container)
+		if err != nil {
+			return nil, fmt.Errorf("docker container inspect failed: %w", err)
+		}
+
+		reader, err := cli.ContainerLogs(ctx, args.Container, container.LogsOptions{
+			ShowStdout: true,
+			ShowStderr: true,
+			Tail:       args.Tail,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("docker container logs failed: %w", err)
+		}
+		defer reader.Close()
+
+		// TTY containers get a single unframed stream; everything else is
+		// multiplexed with an 8-byte stream-type/size header per chunk that
+		// stdcopy.StdCopy strips out, per cli.ContainerLogs' doc comment.
+		var out bytes.Buffer
+		if info.Config != nil && info.Config.Tty {
+			buf := make([]byte, 64*1024)
+			n, _ := reader.Read(buf)
+			out.Write(buf[:n])
+		} else if _, err := stdcopy.StdCopy(&out, &out, io.LimitReader(reader, 64*1024)); err != nil && out.Len() == 0 {
+			return nil, fmt.Errorf("docker container logs failed: %w", err)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: out.String()}},
+		}, nil
+	}
+}
+
+func containerInspectHandler(cli *client.Client) mcp.ToolHandler {
+	return func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args struct {
+			Container string `json:"container"`
+		}
+		json.Unmarshal(req.Params.Arguments, &args)
+		if args.Container == "" {
+			return nil, fmt.Errorf("container is required")
+		}
+		info, err := cli.ContainerInspect(ctx, args.Container)
+		if err != nil {
+			return nil, fmt.Errorf("docker container inspect failed: %w", err)
+		}
+		return jsonResult(info)
+	}
+}
+
+func imageListHandler(cli *client.Client) mcp.ToolHandler {
+	return func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		images, err := cli.ImageList(ctx, image.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("docker image list failed: %w", err)
+		}
+		return jsonResult(images)
+	}
+}
+
+func volumeListHandler(cli *client.Client) mcp.ToolHandler {
+	return func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		volumes, err := cli.VolumeList(ctx, volume.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("docker volume list failed: %w", err)
+		}
+		return jsonResult(volumes.Volumes)
+	}
+}
+
+func networkListHandler(cli *client.Client) mcp.ToolHandler {
+	return func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		networks, err := cli.NetworkList(ctx, types.NetworkListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("docker network list failed: %w", err)
+		}
+		return jsonResult(networks)
+	}
+}
+
+func eventsStreamHandler(cli *client.Client) mcp.ToolHandler {
+	return func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var args struct {
+			Seconds int `json:"seconds"`
+		}
+		json.Unmarshal(req.Params.Arguments, &args)
+		if args.Seconds <= 0 {
+			args.Seconds = 5
+		}
+
+		collectCtx, cancel := context.WithTimeout(ctx, time.Duration(args.Seconds)*time.Second)
+		defer cancel()
+
+		msgs, errs := cli.Events(collectCtx, types.EventsOptions{})
+
+		var collected []events.Message
+		for {
+			select {
+			case msg := <-msgs:
+				collected = append(collected, msg)
+			case err := <-errs:
+				if err != nil && collectCtx.Err() == nil {
+					return nil, fmt.Errorf("docker events stream failed: %w", err)
+				}
+				return jsonResult(collected)
+			case <-collectCtx.Done():
+				return jsonResult(collected)
+			}
+		}
+	}
+}
+
+// jsonResult marshals v and wraps it as the text content of a tool result,
+// matching how the bridge relays structured Portainer responses.
+func jsonResult(v any) (*mcp.CallToolResult, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal docker response: %w", err)
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: string(data)}},
+	}, nil
+}
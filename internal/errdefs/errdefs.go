@@ -0,0 +1,182 @@
+// Package errdefs defines a small taxonomy of error categories, modeled on
+// Docker's own errdefs package, that MCP tool handlers can wrap upstream
+// failures in. Giving LLM clients a stable `code` field lets them branch on
+// error class instead of pattern-matching English error text.
+package errdefs
+
+import "errors"
+
+// NotFound is implemented by errors representing a missing resource.
+type NotFound interface{ NotFound() }
+
+// InvalidParameter is implemented by errors representing a malformed or
+// out-of-range request argument.
+type InvalidParameter interface{ InvalidParameter() }
+
+// Conflict is implemented by errors representing a request that can't
+// complete because of the resource's current state.
+type Conflict interface{ Conflict() }
+
+// Unauthorized is implemented by errors representing missing or invalid
+// credentials.
+type Unauthorized interface{ Unauthorized() }
+
+// Forbidden is implemented by errors representing a caller who is
+// authenticated but not permitted to perform the request.
+type Forbidden interface{ Forbidden() }
+
+// Unavailable is implemented by errors representing a dependency that is
+// temporarily unreachable or overloaded.
+type Unavailable interface{ Unavailable() }
+
+// System is implemented by errors representing an unexpected failure with
+// no more specific category.
+type System interface{ System() }
+
+type notFoundErr struct{ error }
+
+func (notFoundErr) NotFound() {}
+
+type invalidParameterErr struct{ error }
+
+func (invalidParameterErr) InvalidParameter() {}
+
+type conflictErr struct{ error }
+
+func (conflictErr) Conflict() {}
+
+type unauthorizedErr struct{ error }
+
+func (unauthorizedErr) Unauthorized() {}
+
+type forbiddenErr struct{ error }
+
+func (forbiddenErr) Forbidden() {}
+
+type unavailableErr struct{ error }
+
+func (unavailableErr) Unavailable() {}
+
+type systemErr struct{ error }
+
+func (systemErr) System() {}
+
+// AsNotFound wraps err so that IsNotFound(err) reports true.
+func AsNotFound(err error) error {
+	if err == nil {
+		return nil
+	}
+	return notFoundErr{err}
+}
+
+// AsInvalidParameter wraps err so that IsInvalidParameter(err) reports true.
+func AsInvalidParameter(err error) error {
+	if err == nil {
+		return nil
+	}
+	return invalidParameterErr{err}
+}
+
+// AsConflict wraps err so that IsConflict(err) reports true.
+func AsConflict(err error) error {
+	if err == nil {
+		return nil
+	}
+	return conflictErr{err}
+}
+
+// AsUnauthorized wraps err so that IsUnauthorized(err) reports true.
+func AsUnauthorized(err error) error {
+	if err == nil {
+		return nil
+	}
+	return unauthorizedErr{err}
+}
+
+// AsForbidden wraps err so that IsForbidden(err) reports true.
+func AsForbidden(err error) error {
+	if err == nil {
+		return nil
+	}
+	return forbiddenErr{err}
+}
+
+// AsUnavailable wraps err so that IsUnavailable(err) reports true.
+func AsUnavailable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return unavailableErr{err}
+}
+
+// AsSystem wraps err so that IsSystem(err) reports true.
+func AsSystem(err error) error {
+	if err == nil {
+		return nil
+	}
+	return systemErr{err}
+}
+
+func IsNotFound(err error) bool         { var e NotFound; return errors.As(err, &e) }
+func IsInvalidParameter(err error) bool { var e InvalidParameter; return errors.As(err, &e) }
+func IsConflict(err error) bool         { var e Conflict; return errors.As(err, &e) }
+func IsUnauthorized(err error) bool     { var e Unauthorized; return errors.As(err, &e) }
+func IsForbidden(err error) bool        { var e Forbidden; return errors.As(err, &e) }
+func IsUnavailable(err error) bool      { var e Unavailable; return errors.As(err, &e) }
+func IsSystem(err error) bool           { var e System; return errors.As(err, &e) }
+
+// Code returns the machine-readable code for err's category, defaulting to
+// "SYSTEM" when err doesn't match any known marker interface.
+func Code(err error) string {
+	switch {
+	case IsNotFound(err):
+		return "NOT_FOUND"
+	case IsInvalidParameter(err):
+		return "INVALID_PARAMETER"
+	case IsConflict(err):
+		return "CONFLICT"
+	case IsUnauthorized(err):
+		return "UNAUTHORIZED"
+	case IsForbidden(err):
+		return "FORBIDDEN"
+	case IsUnavailable(err):
+		return "UNAVAILABLE"
+	default:
+		return "SYSTEM"
+	}
+}
+
+// Payload is the machine-readable shape an MCP tool-result error should
+// carry so callers can branch on err.Code instead of parsing err.Message.
+type Payload struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ToPayload converts err into its wire-level Payload representation.
+func ToPayload(err error) Payload {
+	return Payload{Code: Code(err), Message: err.Error()}
+}
+
+// FromHTTPStatus classifies an upstream Portainer HTTP response into the
+// errdefs taxonomy, wrapping err accordingly.
+func FromHTTPStatus(status int, err error) error {
+	switch {
+	case status == 404:
+		return AsNotFound(err)
+	case status == 401:
+		return AsUnauthorized(err)
+	case status == 403:
+		return AsForbidden(err)
+	case status == 409:
+		return AsConflict(err)
+	case status == 400 || status == 422:
+		return AsInvalidParameter(err)
+	case status == 503:
+		return AsUnavailable(err)
+	case status >= 500:
+		return AsSystem(err)
+	default:
+		return err
+	}
+}
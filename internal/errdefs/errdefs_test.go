@@ -0,0 +1,44 @@
+package errdefs
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFromHTTPStatus(t *testing.T) {
+	base := errors.New("boom")
+
+	cases := []struct {
+		status int
+		check  func(error) bool
+	}{
+		{404, IsNotFound},
+		{401, IsUnauthorized},
+		{403, IsForbidden},
+		{409, IsConflict},
+		{400, IsInvalidParameter},
+		{422, IsInvalidParameter},
+		{503, IsUnavailable},
+		{500, IsSystem},
+		{599, IsSystem},
+	}
+	for _, c := range cases {
+		err := FromHTTPStatus(c.status, base)
+		if !c.check(err) {
+			t.Errorf("FromHTTPStatus(%d, ...) did not classify as expected", c.status)
+		}
+	}
+}
+
+func TestFromHTTPStatusPassesThroughUnknown(t *testing.T) {
+	base := errors.New("boom")
+	if err := FromHTTPStatus(200, base); err != base {
+		t.Fatalf("status with no mapping should return err unchanged, got %v", err)
+	}
+}
+
+func TestCodeDefaultsToSystem(t *testing.T) {
+	if Code(errors.New("plain")) != "SYSTEM" {
+		t.Fatal("an unclassified error should default to SYSTEM")
+	}
+}